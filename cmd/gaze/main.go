@@ -0,0 +1,125 @@
+/**
+ * Gaze (https://github.com/wtetsu/gaze/)
+ * Copyright 2020-present wtetsu
+ * Licensed under MIT
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wtetsu/gaze/pkg/config"
+	"github.com/wtetsu/gaze/pkg/gazer"
+	"github.com/wtetsu/gaze/pkg/logger"
+)
+
+// schedulerKind maps the -scheduler flag's string value to gazer.SchedulerKind.
+func schedulerKind(name string) (gazer.SchedulerKind, error) {
+	switch name {
+	case "", "restart":
+		return gazer.SchedulerRestart, nil
+	case "serial":
+		return gazer.SchedulerSerial, nil
+	case "parallel":
+		return gazer.SchedulerParallel, nil
+	case "debounce":
+		return gazer.SchedulerDebounce, nil
+	default:
+		return gazer.SchedulerRestart, fmt.Errorf("unknown -scheduler %q (want restart, serial, parallel or debounce)", name)
+	}
+}
+
+// excludeFlags collects repeated -exclude flags into a []string.
+type excludeFlags []string
+
+func (e *excludeFlags) String() string {
+	return strings.Join(*e, ",")
+}
+
+func (e *excludeFlags) Set(v string) error {
+	*e = append(*e, v)
+	return nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	flags := flag.NewFlagSet("gaze", flag.ContinueOnError)
+
+	configPath := flags.String("c", "gaze.yml", "path to the command config")
+	timeout := flags.Int("t", 0, "command timeout in seconds (0 = no timeout)")
+	restart := flags.Bool("r", false, "kill and restart the running command on a new event instead of waiting for it to finish")
+	rescanInterval := flags.Duration("rescan-interval", 0, "periodically rescan watched files for changes fsnotify missed, e.g. on NFS/SMB shares (0 disables it)")
+	detectRemove := flags.Bool("detect-remove", false, "surface file deletions as notify events")
+	jsonMode := flags.Bool("json", false, "emit NDJSON lifecycle records on stdout instead of human-oriented logging, for editor plugins and other machine consumers")
+	schedulerName := flags.String("scheduler", "restart", "how to turn triggers into runs: restart (default: the built-in path, which waits for each run unless -r is set), serial, parallel or debounce")
+	parallelism := flags.Int("parallelism", 4, "max concurrent runs for -scheduler=parallel")
+	debounceWindow := flags.Duration("debounce-window", 500*time.Millisecond, "coalescing window for -scheduler=debounce")
+	history := flags.Bool("history", false, "print every recorded run (command, file, exit code, output) on exit")
+	var excludes excludeFlags
+	flags.Var(&excludes, "exclude", "a .gitignore-style exclude pattern (repeatable)")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	kind, err := schedulerKind(*schedulerName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: gaze [flags] <pattern>...")
+		flags.PrintDefaults()
+		return 2
+	}
+
+	commandConfigs, err := config.New(*configPath)
+	if err != nil {
+		logger.ErrorObject(err)
+		return 1
+	}
+
+	g := gazer.New(patterns, excludes, *rescanInterval, *detectRemove)
+	defer g.Close()
+
+	if kind != gazer.SchedulerRestart {
+		g.UseScheduler(kind, *parallelism, *debounceWindow)
+	}
+
+	runErr := g.Run(commandConfigs, *timeout, *restart, *jsonMode)
+
+	if *history {
+		printHistory(g.History())
+	}
+
+	if runErr != nil {
+		logger.ErrorObject(runErr)
+		return 1
+	}
+	return 0
+}
+
+// printHistory prints every recorded run, oldest first, in the order
+// Gazer.History() returns them.
+func printHistory(records []gazer.RunRecord) {
+	for _, r := range records {
+		durationMs := (r.End - r.Start) / int64(time.Millisecond)
+		fmt.Printf("[%s] %s -> exit %d (%dms)\n", r.Cmd, r.File, r.ExitCode, durationMs)
+		if r.Stdout != "" {
+			fmt.Print(r.Stdout)
+		}
+		if r.Stderr != "" {
+			fmt.Fprint(os.Stderr, r.Stderr)
+		}
+	}
+}
@@ -7,37 +7,85 @@
 package gazer
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	stdtime "time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/wtetsu/gaze/pkg/config"
-	"github.com/wtetsu/gaze/pkg/fs"
 	"github.com/wtetsu/gaze/pkg/logger"
 	"github.com/wtetsu/gaze/pkg/notify"
+	"github.com/wtetsu/gaze/pkg/scheduler"
 	"github.com/wtetsu/gaze/pkg/time"
 )
 
+// defaultMaxWatchDirs caps how many directories a single Gazer will ask
+// fsnotify to watch before giving up.
+const defaultMaxWatchDirs = 256
+
+// SchedulerKind selects which pkg/scheduler.Strategy UseScheduler builds.
+type SchedulerKind int
+
+// The available scheduler strategies. SchedulerRestart always kills and
+// restarts a superseded run. When UseScheduler hasn't been called, Run uses
+// its built-in path instead, which waits for each run to finish unless Run's
+// restart argument is true.
+const (
+	SchedulerRestart SchedulerKind = iota
+	SchedulerSerial
+	SchedulerParallel
+	SchedulerDebounce
+)
+
 // Gazer gazes filesystem.
 type Gazer struct {
-	patterns []string
-	notify   *notify.Notify
-	isClosed bool
-	counter  uint64
+	patterns  []string
+	excludes  []string
+	notify    *notify.Notify
+	isClosed  bool
+	counter   uint64
+	timeout   int
+	sink      *jsonSink
+	history   *history
+	scheduler scheduler.Strategy
 }
 
-// New returns a new Gazer.
-func New(patterns []string) *Gazer {
+// New returns a new Gazer. rescanInterval, when greater than 0, enables a
+// periodic rescan of the watched files in addition to fsnotify events,
+// which is useful on network/virtualized filesystems where fsnotify events
+// are dropped (e.g. Docker-mounted volumes, NFS/SMB shares). excludes is a
+// set of .gitignore-style patterns (supporting "**") applied both when
+// discovering watchDirs and when deciding whether a notified file should
+// trigger a command, so large subtrees like node_modules or vendor never
+// need to be matched against manually. detectRemove opts into surfacing
+// file deletions as notify events, so commands can be bound to them.
+func New(patterns []string, excludes []string, rescanInterval stdtime.Duration, detectRemove bool) *Gazer {
 	cleanPatterns := make([]string, len(patterns))
 	for i, p := range patterns {
 		cleanPatterns[i] = filepath.Clean(p)
 	}
 
-	notify, _ := notify.New(cleanPatterns)
+	notify, _ := notify.New(cleanPatterns, defaultMaxWatchDirs, rescanInterval, excludes)
+	// notify.Excludes is excludes merged with whatever the nearest
+	// .gazeignore contributes; reuse it here so the event-level exclude
+	// check below sees the same excludes that picked the watchDirs.
+	mergedExcludes := excludes
+	if notify != nil {
+		notify.DetectRemove = detectRemove
+		mergedExcludes = notify.Excludes
+	}
 	return &Gazer{
 		patterns: cleanPatterns,
+		excludes: mergedExcludes,
 		notify:   notify,
 		isClosed: false,
+		history:  newHistory(),
 	}
 }
 
@@ -46,12 +94,45 @@ func (g *Gazer) Close() {
 	if g.isClosed {
 		return
 	}
+	if g.scheduler != nil {
+		g.scheduler.Close()
+	}
 	g.notify.Close()
 	g.isClosed = true
 }
 
-// Run starts to gaze.
-func (g *Gazer) Run(configs *config.Config, timeout int, restart bool) error {
+// UseScheduler switches Run to execute commands through a pkg/scheduler
+// strategy instead of its built-in path (wait for each run, or kill and
+// restart it when Run's restart argument is true). parallelism is
+// only used by SchedulerParallel, and window only by SchedulerDebounce.
+func (g *Gazer) UseScheduler(kind SchedulerKind, parallelism int, window stdtime.Duration) {
+	switch kind {
+	case SchedulerSerial:
+		g.scheduler = scheduler.Serial(g.runCommand)
+	case SchedulerParallel:
+		g.scheduler = scheduler.Parallel(parallelism, g.runCommand)
+	case SchedulerDebounce:
+		g.scheduler = scheduler.Debounce(window, g.runCommand)
+	default:
+		g.scheduler = scheduler.Restart(g.runCommand)
+	}
+}
+
+// History returns the recorded command executions (only the most recent
+// historySize are kept), oldest first, so users
+// can see what fired, in what order, and which runs failed.
+func (g *Gazer) History() []RunRecord {
+	return g.history.list()
+}
+
+// Run starts to gaze. When jsonMode is true, lifecycle events are emitted as
+// NDJSON records on stdout instead of the human-oriented logger.Notice*
+// output, for editor plugins and other machine consumers.
+func (g *Gazer) Run(configs *config.Config, timeout int, restart bool, jsonMode bool) error {
+	g.timeout = timeout
+	if jsonMode {
+		g.sink = newJSONSink(os.Stdout)
+	}
 	err := g.repeatRunAndWait(configs, timeout, restart)
 	return err
 }
@@ -77,22 +158,38 @@ func (g *Gazer) repeatRunAndWait(commandConfigs *config.Config, timeout int, res
 			if ok && event.Op|flag == 0 {
 				continue
 			}
+			if notify.MatchExclude(g.excludes, event.Name) {
+				continue
+			}
 			if !matchAny(g.patterns, event.Name) {
 				continue
 			}
+			if g.sink != nil {
+				g.sink.Notify(event.Name, event.Op.String(), event.Time)
+			}
 			modifiedTime := time.GetFileModifiedTime(event.Name)
 			if (modifiedTime - lastExecutionTime) < ignorePeriod {
 				continue
 			}
 
-			g.counter++
-			commandString := getAppropriateCommand(event.Name, commandConfigs)
-			if commandString == "" {
+			c := matchCommand(event.Name, commandConfigs)
+			if c == nil {
 				logger.Debug("Command not found: %s", event.Name)
 				continue
 			}
+			lastExecutionTime = time.Now()
+
+			if g.scheduler != nil {
+				g.scheduler.Submit(c.Run, event.Name)
+				continue
+			}
 
-			logger.NoticeWithBlank("[%s]", commandString)
+			g.counter++
+			commandString := render(c.Run, event.Name)
+
+			if g.sink == nil {
+				logger.NoticeWithBlank("[%s]", commandString)
+			}
 
 			if ongoingCommand != nil {
 				kill(ongoingCommand, "Restart")
@@ -100,20 +197,22 @@ func (g *Gazer) repeatRunAndWait(commandConfigs *config.Config, timeout int, res
 			}
 
 			cmd := createCommand(commandString)
-			lastExecutionTime = time.Now()
+			stdout, stderr := g.attachCapture(cmd)
+			startedAt := time.Now()
+			if err := cmd.Start(); err != nil {
+				g.reportExit(cmd, event.Name, commandString, startedAt, stdout, stderr, err)
+				continue
+			}
+			emitExec(g.sink, cmd, commandString, event.Name, startedAt)
 			if !restart {
-				err := executeCommandOrTimeout(cmd, timeout)
-				if err != nil {
-					logger.NoticeObject(err)
-				}
+				err := waitCommandOrTimeout(cmd, timeout)
+				g.reportExit(cmd, event.Name, commandString, startedAt, stdout, stderr, err)
 			} else {
 				// restartable
 				ongoingCommand = cmd
 				go func() {
-					err := executeCommandOrTimeout(cmd, timeout)
-					if err != nil {
-						logger.NoticeObject(err)
-					}
+					err := waitCommandOrTimeout(cmd, timeout)
+					g.reportExit(cmd, event.Name, commandString, startedAt, stdout, stderr, err)
 					ongoingCommand = nil
 				}()
 			}
@@ -126,10 +225,87 @@ func (g *Gazer) repeatRunAndWait(commandConfigs *config.Config, timeout int, res
 	return nil
 }
 
+// attachCapture wires cmd's stdout/stderr so every run's output ends up in
+// the buffers it returns, regardless of where else it's also going: forwarded
+// to the sink as NDJSON "output" records in JSON mode, or left on whatever
+// createCommand set (e.g. an inherited terminal) otherwise. reportExit uses
+// the buffers to populate RunRecord.Stdout/Stderr, so History() has real
+// content no matter which mode or scheduler Gazer is running under.
+func (g *Gazer) attachCapture(cmd *exec.Cmd) (stdout, stderr *bytes.Buffer) {
+	stdout = &bytes.Buffer{}
+	stderr = &bytes.Buffer{}
+
+	if g.sink != nil {
+		getPID := func() int { return pid(cmd) }
+		cmd.Stdout = io.MultiWriter(stdout, &sinkWriter{sink: g.sink, stream: "stdout", pid: getPID})
+		cmd.Stderr = io.MultiWriter(stderr, &sinkWriter{sink: g.sink, stream: "stderr", pid: getPID})
+		return stdout, stderr
+	}
+
+	if cmd.Stdout != nil {
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, stdout)
+	} else {
+		cmd.Stdout = stdout
+	}
+	if cmd.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, stderr)
+	} else {
+		cmd.Stderr = stderr
+	}
+	return stdout, stderr
+}
+
+func pid(cmd *exec.Cmd) int {
+	if cmd.Process == nil {
+		return 0
+	}
+	return cmd.Process.Pid
+}
+
+// emitExec emits the sink's "exec" record for cmd. Callers must only call
+// this once cmd.Start has already returned (on the same goroutine that
+// called it), so pid is populated instead of always reading 0, letting
+// consumers correlate "exec"/"output"/"exit" records for the same run by pid.
+func emitExec(sink *jsonSink, cmd *exec.Cmd, commandString string, file string, startedAt int64) {
+	if sink == nil {
+		return
+	}
+	sink.Exec(commandString, file, pid(cmd), startedAt)
+}
+
+// reportExit records a RunRecord for a finished command and emits the
+// "exit" record for the sink, or reports the error through the logger, as
+// appropriate.
+func (g *Gazer) reportExit(cmd *exec.Cmd, file string, commandString string, startedAt int64, stdout, stderr *bytes.Buffer, err error) {
+	endedAt := time.Now()
+
+	g.history.add(RunRecord{
+		Cmd:      commandString,
+		File:     file,
+		Start:    startedAt,
+		End:      endedAt,
+		ExitCode: exitCode(err),
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+	})
+
+	if g.sink == nil {
+		if err != nil {
+			logger.NoticeObject(err)
+		}
+		return
+	}
+	if err != nil && exitCode(err) == -1 {
+		g.sink.Error(err)
+	}
+	durationMs := (endedAt - startedAt) / 1000000
+	g.sink.Exit(pid(cmd), exitCode(err), durationMs)
+}
+
 func matchAny(watchFiles []string, s string) bool {
 	result := false
 	for _, f := range watchFiles {
-		if fs.GlobMatch(f, s) {
+		if notify.MatchPattern(f, s) {
 			result = true
 			break
 		}
@@ -137,19 +313,84 @@ func matchAny(watchFiles []string, s string) bool {
 	return result
 }
 
-func getAppropriateCommand(filePath string, commandConfigs *config.Config) string {
-	var result string
+// matchCommand returns the first command config whose Ext/Re matches
+// filePath, or nil if none does. Unlike the old getAppropriateCommand, it
+// leaves c.Run unrendered so callers can choose how to render it (a single
+// file via render, or a coalesced batch via renderFiles).
+func matchCommand(filePath string, commandConfigs *config.Config) *config.Command {
 	for _, c := range commandConfigs.Commands {
 		if c.Run == "" || c.Ext == "" && c.Re == "" {
 			continue
 		}
 		if c.Match(filePath) {
-			command := render(c.Run, filePath)
-			result = command
-			break
+			return c
 		}
 	}
-	return result
+	return nil
+}
+
+// runCommand executes cmdTemplate for the given files. It's used as the
+// scheduler.RunFunc for whichever Strategy UseScheduler configured: a
+// single file renders cmdTemplate the same way Run's built-in path does, while several files (as Debounce coalesces) render it with
+// {{.Files}} available. If ctx is canceled before the command finishes
+// (Restart superseding it with a newer run), the process is killed.
+func (g *Gazer) runCommand(ctx context.Context, cmdTemplate string, files []string) {
+	var commandString string
+	switch {
+	case len(files) == 1:
+		commandString = render(cmdTemplate, files[0])
+	case len(files) > 1:
+		commandString = renderFiles(cmdTemplate, files)
+	}
+	if commandString == "" {
+		return
+	}
+
+	g.counter++
+	cmd := createCommand(commandString)
+	stdout, stderr := g.attachCapture(cmd)
+
+	startedAt := time.Now()
+	if g.sink == nil {
+		logger.NoticeWithBlank("[%s]", commandString)
+	}
+	if err := cmd.Start(); err != nil {
+		g.reportExit(cmd, strings.Join(files, ","), commandString, startedAt, stdout, stderr, err)
+		return
+	}
+	emitExec(g.sink, cmd, commandString, files[0], startedAt)
+
+	killed := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			kill(cmd, "Restart")
+		case <-killed:
+		}
+	}()
+
+	err := waitCommandOrTimeout(cmd, g.timeout)
+	close(killed)
+
+	g.reportExit(cmd, strings.Join(files, ","), commandString, startedAt, stdout, stderr, err)
+}
+
+// renderFiles renders cmdTemplate as a Go template exposing the coalesced
+// set of touched files as {{.Files}}, for the Debounce scheduler strategy.
+func renderFiles(cmdTemplate string, files []string) string {
+	tmpl, err := texttemplate.New("command").Parse(cmdTemplate)
+	if err != nil {
+		logger.Error("invalid command template %q: %v", cmdTemplate, err)
+		return ""
+	}
+
+	var buf bytes.Buffer
+	data := struct{ Files []string }{Files: files}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		logger.Error("failed to render command template %q: %v", cmdTemplate, err)
+		return ""
+	}
+	return buf.String()
 }
 
 // Counter returns the current execution counter
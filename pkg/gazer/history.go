@@ -0,0 +1,68 @@
+/**
+ * Gaze (https://github.com/wtetsu/gaze/)
+ * Copyright 2020-present wtetsu
+ * Licensed under MIT
+ */
+
+package gazer
+
+import "sync"
+
+// historySize bounds the in-memory run history so a long watch session
+// doesn't grow it unbounded.
+const historySize = 256
+
+// RunRecord captures one command execution: what ran, for which file(s), when
+// it started and ended, how it exited, and everything it printed. It's what
+// Gazer.History() surfaces, for debugging a flaky watch setup after the
+// fact.
+type RunRecord struct {
+	Cmd      string
+	File     string
+	Start    int64
+	End      int64
+	ExitCode int
+	Stdout   string
+	Stderr   string
+}
+
+// history is a fixed-size ring buffer of the most recent RunRecords.
+type history struct {
+	mu      sync.Mutex
+	records []RunRecord
+	next    int
+	full    bool
+}
+
+func newHistory() *history {
+	return &history{records: make([]RunRecord, historySize)}
+}
+
+func (h *history) add(r RunRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.records[h.next] = r
+	h.next++
+	if h.next == len(h.records) {
+		h.next = 0
+		h.full = true
+	}
+}
+
+// list returns every recorded run, oldest first.
+func (h *history) list() []RunRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.full {
+		out := make([]RunRecord, h.next)
+		copy(out, h.records[:h.next])
+		return out
+	}
+
+	out := make([]RunRecord, len(h.records))
+	n := copy(out, h.records[h.next:])
+	copy(out[n:], h.records[:h.next])
+	return out
+}
@@ -0,0 +1,120 @@
+/**
+ * Gaze (https://github.com/wtetsu/gaze/)
+ * Copyright 2020-present wtetsu
+ * Licensed under MIT
+ */
+
+package gazer
+
+import (
+	"encoding/json"
+	"io"
+	"os/exec"
+	"sync"
+)
+
+// jsonSink emits one NDJSON record per line to an io.Writer for each
+// lifecycle event, so editor plugins, watch-based test runners, and log
+// aggregators can subscribe to a well-defined stream instead of
+// screen-scraping colorized output. It's enabled by Gazer.Run's jsonMode
+// argument, which also suppresses the human-oriented logger.Notice* calls.
+type jsonSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func newJSONSink(w io.Writer) *jsonSink {
+	return &jsonSink{writer: w}
+}
+
+type jsonNotifyRecord struct {
+	Type string `json:"type"`
+	File string `json:"file"`
+	Op   string `json:"op"`
+	Time int64  `json:"time"`
+}
+
+type jsonExecRecord struct {
+	Type      string `json:"type"`
+	Cmd       string `json:"cmd"`
+	File      string `json:"file"`
+	PID       int    `json:"pid"`
+	StartedAt int64  `json:"started_at"`
+}
+
+type jsonOutputRecord struct {
+	Type   string `json:"type"`
+	Stream string `json:"stream"`
+	Data   string `json:"data"`
+	PID    int    `json:"pid"`
+}
+
+type jsonExitRecord struct {
+	Type       string `json:"type"`
+	PID        int    `json:"pid"`
+	Code       int    `json:"code"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type jsonErrorRecord struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (s *jsonSink) emit(record interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	s.writer.Write(append(line, '\n'))
+}
+
+func (s *jsonSink) Notify(file string, op string, t int64) {
+	s.emit(jsonNotifyRecord{Type: "notify", File: file, Op: op, Time: t})
+}
+
+func (s *jsonSink) Exec(cmdString string, file string, pid int, startedAt int64) {
+	s.emit(jsonExecRecord{Type: "exec", Cmd: cmdString, File: file, PID: pid, StartedAt: startedAt})
+}
+
+func (s *jsonSink) Output(stream string, data string, pid int) {
+	s.emit(jsonOutputRecord{Type: "output", Stream: stream, Data: data, PID: pid})
+}
+
+func (s *jsonSink) Exit(pid int, code int, durationMs int64) {
+	s.emit(jsonExitRecord{Type: "exit", PID: pid, Code: code, DurationMs: durationMs})
+}
+
+func (s *jsonSink) Error(err error) {
+	s.emit(jsonErrorRecord{Type: "error", Message: err.Error()})
+}
+
+// exitCode extracts a process exit code from the error returned by running
+// a command, following the same convention as os.Exit: 0 for a nil error,
+// the process's own code for an *exec.ExitError, and -1 if it can't be
+// determined (e.g. the command was killed by a signal or never started).
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// sinkWriter adapts a jsonSink into an io.Writer for one of a command's
+// output streams, forwarding every Write as an "output" record.
+type sinkWriter struct {
+	sink   *jsonSink
+	stream string
+	pid    func() int
+}
+
+func (w *sinkWriter) Write(p []byte) (int, error) {
+	w.sink.Output(w.stream, string(p), w.pid())
+	return len(p), nil
+}
@@ -0,0 +1,159 @@
+/**
+ * Gaze (https://github.com/wtetsu/gaze/)
+ * Copyright 2020-present wtetsu
+ * Licensed under MIT
+ */
+
+package notify
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/wtetsu/gaze/pkg/fs"
+	"github.com/wtetsu/gaze/pkg/logger"
+)
+
+// gazeignoreFileName is the ignore file, using .gitignore syntax, that New
+// looks for by walking upward from the working directory.
+const gazeignoreFileName = ".gazeignore"
+
+// loadGazeignore reads exclude patterns from the nearest .gazeignore found by
+// walking upward from the current working directory, so users don't have to
+// pass the same excludes on every invocation.
+func loadGazeignore() []string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+
+	for {
+		path := filepath.Join(dir, gazeignoreFileName)
+		if fs.IsFile(path) {
+			return readIgnoreFile(path)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+func readIgnoreFile(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		logger.Debug("failed to read %s: %v", path, err)
+		return nil
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns
+}
+
+// MatchExclude reports whether path matches any of the given .gitignore-style
+// exclude patterns, including "**" for matching any number of path segments.
+// Patterns are evaluated in order, with a later pattern able to re-include a
+// path by prefixing it with "!", as in .gitignore.
+func MatchExclude(excludes []string, path string) bool {
+	cleaned := filepath.ToSlash(filepath.Clean(path))
+	base := filepath.Base(cleaned)
+
+	matched := false
+	for _, raw := range excludes {
+		pattern := strings.TrimSpace(raw)
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+
+		negate := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		anchored := strings.HasPrefix(pattern, "/")
+		pattern = strings.TrimPrefix(pattern, "/")
+		pattern = strings.TrimSuffix(pattern, "/")
+
+		re, err := globToRegexp(pattern)
+		if err != nil {
+			logger.Debug("invalid exclude pattern %q: %v", raw, err)
+			continue
+		}
+
+		var hit bool
+		if anchored || strings.Contains(pattern, "/") {
+			hit = re.MatchString(cleaned)
+		} else {
+			hit = re.MatchString(base) || re.MatchString(cleaned)
+		}
+
+		if hit {
+			matched = !negate
+		}
+	}
+	return matched
+}
+
+// MatchPattern reports whether path matches pattern, with "**" matching any
+// number of path segments in addition to the usual "*"/"?" glob syntax.
+// Unlike MatchExclude, pattern is a single positive glob rather than a
+// .gitignore-style list, so it's suited to the inclusion patterns passed to
+// notify.New, not exclude lists.
+func MatchPattern(pattern string, path string) bool {
+	cleaned := filepath.ToSlash(filepath.Clean(path))
+
+	re, err := globToRegexp(filepath.ToSlash(pattern))
+	if err != nil {
+		logger.Debug("invalid pattern %q: %v", pattern, err)
+		return false
+	}
+	return re.MatchString(cleaned)
+}
+
+// globToRegexp compiles a .gitignore-style glob (where "**" matches any
+// number of path segments) into a regular expression anchored to a full
+// match.
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch c {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				i++
+				if i+1 < len(runes) && runes[i+1] == '/' {
+					b.WriteString("(?:.*/)?")
+					i++
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '.', '+', '(', ')', '|', '^', '$', '\\':
+			b.WriteString("\\")
+			b.WriteRune(c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
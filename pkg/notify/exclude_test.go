@@ -0,0 +1,69 @@
+/**
+ * Gaze (https://github.com/wtetsu/gaze/)
+ * Copyright 2020-present wtetsu
+ * Licensed under MIT
+ */
+
+package notify
+
+import "testing"
+
+func TestMatchExclude(t *testing.T) {
+	tests := []struct {
+		name     string
+		excludes []string
+		path     string
+		want     bool
+	}{
+		{"no patterns", nil, "main.go", false},
+		{"basename match", []string{"*.log"}, "tmp/debug.log", true},
+		{"basename miss", []string{"*.log"}, "tmp/debug.txt", false},
+		{"doublestar recursive dir", []string{"**/node_modules/**"}, "a/b/node_modules/x/y.js", true},
+		{"doublestar recursive dir miss", []string{"**/node_modules/**"}, "a/b/c.js", false},
+		{"anchored root matches itself", []string{"/vendor"}, "vendor", true},
+		{"anchored root does not match nested path", []string{"/vendor"}, "vendor/pkg/a.go", false},
+		{"anchored root does not match elsewhere", []string{"/vendor"}, "a/vendor", false},
+		{"negation re-includes", []string{"*.go", "!keep.go"}, "keep.go", false},
+		{"negation leaves others excluded", []string{"*.go", "!keep.go"}, "other.go", true},
+		{"later pattern wins", []string{"!a.go", "a.go"}, "a.go", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchExclude(tt.excludes, tt.path); got != tt.want {
+				t.Errorf("MatchExclude(%v, %q) = %v, want %v", tt.excludes, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"plain extension", "*.go", "main.go", true},
+		{"plain extension nested miss", "*.go", "src/main.go", false},
+		{"doublestar crosses dirs", "src/**/*.go", "src/a/b/main.go", true},
+		{"doublestar zero dirs", "src/**/*.go", "src/main.go", true},
+		{"doublestar requires prefix", "src/**/*.go", "other/main.go", false},
+		{"question mark single char", "a?.txt", "ab.txt", true},
+		{"question mark rejects extra char", "a?.txt", "abc.txt", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchPattern(tt.pattern, tt.path); got != tt.want {
+				t.Errorf("MatchPattern(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGlobToRegexpInvalidPattern(t *testing.T) {
+	if _, err := globToRegexp("["); err == nil {
+		t.Fatal("expected an error compiling an unterminated character class")
+	}
+}
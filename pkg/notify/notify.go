@@ -8,8 +8,12 @@ package notify
 
 import (
 	"errors"
+	iofs "io/fs"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	stdtime "time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/wtetsu/gaze/pkg/fs"
@@ -25,15 +29,24 @@ type Notify struct {
 	Errors                  chan error
 	watcher                 *fsnotify.Watcher
 	isClosed                bool
+	mu                      sync.Mutex // guards times, rescanTimes and watchedDirs
 	times                   map[string]int64
 	pendingPeriod           int64
 	regardRenameAsModPeriod int64
 	detectCreate            bool
+	patterns                []string
+	Excludes                []string
+	RescanInterval          stdtime.Duration
+	rescanTimes             map[string]int64
+	watchedDirs             map[string]bool
+	DetectRemove            bool
+	done                    chan struct{}
 }
 
 // Event represents a single file system notification.
 type Event struct {
 	Name string
+	Op   Op
 	Time int64
 }
 
@@ -45,31 +58,42 @@ func (n *Notify) Close() {
 	if n.isClosed {
 		return
 	}
+	close(n.done)
 	n.watcher.Close()
 	n.isClosed = true
 }
 
-// New creates a Notify
-func New(patterns []string, maxWatchDirs int) (*Notify, error) {
+// New creates a Notify. If rescanInterval is greater than 0, a background
+// goroutine periodically walks the matched files and synthesizes events for
+// changes that fsnotify missed, such as on NFS/SMB shares or inside some
+// container runtimes. excludes is a set of .gitignore-style patterns
+// (supporting "**") applied on top of whatever the nearest .gazeignore file
+// contributes, so that large subtrees like node_modules, .git, vendor or
+// dist are never added as watchDirs.
+func New(patterns []string, maxWatchDirs int, rescanInterval stdtime.Duration, excludes []string) (*Notify, error) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		logger.ErrorObject(err)
 		return nil, err
 	}
 
-	watchDirs := findDirs(patterns, maxWatchDirs)
+	excludes = append(append([]string{}, excludes...), loadGazeignore()...)
+
+	watchDirs := findDirs(patterns, maxWatchDirs, excludes)
 
 	if len(watchDirs) > maxWatchDirs {
 		logger.Error(strings.Join(watchDirs[:maxWatchDirs], "\n") + "\n...")
 		return nil, errors.New("too many watchDirs")
 	}
 
+	watchedDirs := make(map[string]bool, len(watchDirs))
 	for _, t := range watchDirs {
 		err = watcher.Add(t)
 		if err != nil {
 			logger.Error("%s: %v", t, err)
 		} else {
 			logger.Info("gazing at: %s", t)
+			watchedDirs[t] = true
 		}
 	}
 
@@ -81,21 +105,70 @@ func New(patterns []string, maxWatchDirs int) (*Notify, error) {
 		pendingPeriod:           100,
 		regardRenameAsModPeriod: 1000,
 		detectCreate:            true,
+		patterns:                patterns,
+		Excludes:                excludes,
+		RescanInterval:          rescanInterval,
+		rescanTimes:             make(map[string]int64),
+		watchedDirs:             watchedDirs,
+		done:                    make(chan struct{}),
 	}
 
 	go notify.wait()
 
+	if rescanInterval > 0 {
+		notify.seedRescanTimes()
+		go notify.rescanLoop()
+	}
+
 	return notify, nil
 }
 
-func findDirs(patterns []string, maxWatchDirs int) []string {
+// seedRescanTimes records the current modtime of every pattern-matched file
+// before the first rescan tick fires, so rescan only reports files that
+// actually changed after startup instead of treating every pre-existing
+// file as new on its first pass.
+func (n *Notify) seedRescanTimes() {
+	for _, pattern := range n.patterns {
+		files, _ := fs.Find(pattern)
+		for _, f := range files {
+			normalizedName := filepath.Clean(f)
+			modifiedTime := time.GetFileModifiedTime(normalizedName)
+
+			n.mu.Lock()
+			n.rescanTimes[normalizedName] = modifiedTime
+			n.mu.Unlock()
+		}
+	}
+}
+
+// dirSet is the subset of *uniq.Uniq's API findDirs needs, named here so
+// addRecursiveMatchDirs doesn't have to know the concrete type.
+type dirSet interface {
+	Add(string)
+	Len() int
+	List() []string
+}
+
+func findDirs(patterns []string, maxWatchDirs int, excludes []string) []string {
 	targets := uniq.New()
 
 	for _, pattern := range patterns {
+		if strings.Contains(pattern, "**") {
+			// "**" wants true recursive semantics: walk the pattern's static
+			// root ourselves so excluded subtrees are pruned mid-walk,
+			// rather than leaning on fs.Find/fs.GlobMatch, which don't treat
+			// "**" as crossing directory boundaries.
+			addRecursiveMatchDirs(targets, pattern, excludes, maxWatchDirs)
+			if targets.Len() > maxWatchDirs {
+				return targets.List()
+			}
+			continue
+		}
+
 		patternDir := filepath.Dir(pattern)
 
 		realDir := findRealDirectory(patternDir)
-		if len(realDir) > 0 {
+		if len(realDir) > 0 && !MatchExclude(excludes, realDir) {
 			targets.Add(realDir)
 		}
 		if targets.Len() > maxWatchDirs {
@@ -104,6 +177,9 @@ func findDirs(patterns []string, maxWatchDirs int) []string {
 
 		_, dirs1 := fs.Find(pattern)
 		for _, d := range dirs1 {
+			if MatchExclude(excludes, d) {
+				continue
+			}
 			targets.Add(d)
 		}
 		if targets.Len() > maxWatchDirs {
@@ -112,6 +188,9 @@ func findDirs(patterns []string, maxWatchDirs int) []string {
 
 		_, dirs2 := fs.Find(patternDir)
 		for _, d := range dirs2 {
+			if MatchExclude(excludes, d) {
+				continue
+			}
 			targets.Add(d)
 		}
 		if targets.Len() > maxWatchDirs {
@@ -121,6 +200,53 @@ func findDirs(patterns []string, maxWatchDirs int) []string {
 	return targets.List()
 }
 
+// addRecursiveMatchDirs walks pattern's static root (the prefix before its
+// first wildcard segment) and adds the root itself plus every directory
+// containing a file that matches pattern, skipping excluded subtrees
+// outright instead of filtering them out after the fact. Watching the root
+// means directories created or renamed directly under it are seen and
+// watched as they appear.
+func addRecursiveMatchDirs(targets dirSet, pattern string, excludes []string, maxWatchDirs int) {
+	root := findRealDirectory(staticRoot(pattern))
+	if root == "" {
+		root = "."
+	}
+	if !MatchExclude(excludes, root) {
+		targets.Add(root)
+	}
+
+	filepath.WalkDir(root, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if targets.Len() > maxWatchDirs {
+			return filepath.SkipDir
+		}
+		if d.IsDir() {
+			if path != root && MatchExclude(excludes, path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if MatchPattern(pattern, path) {
+			targets.Add(filepath.Dir(path))
+		}
+		return nil
+	})
+}
+
+// staticRoot returns the prefix of pattern up to (but not including) its
+// first wildcard segment, e.g. "src/**/*.go" -> "src".
+func staticRoot(pattern string) string {
+	entries := strings.Split(filepath.ToSlash(pattern), "/")
+	for i, entry := range entries {
+		if strings.IndexAny(entry, "*?[{\\") != -1 {
+			return strings.Join(entries[:i], "/")
+		}
+	}
+	return strings.Join(entries, "/")
+}
+
 func findRealDirectory(path string) string {
 	entries := strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
 
@@ -146,25 +272,53 @@ func (n *Notify) wait() {
 	for {
 		select {
 		case event, ok := <-n.watcher.Events:
+			if !ok {
+				continue
+			}
 
 			normalizedName := filepath.Clean(event.Name)
 
-			if event.Op == fsnotify.Create && fs.IsDir(normalizedName) {
-				logger.Info("gazing at: %s", normalizedName)
-				n.watcher.Add(normalizedName)
+			if event.Op&fsnotify.Create != 0 && fs.IsDir(normalizedName) {
+				// Editors write via rename-into-place and tools like `mv src
+				// new` can bring a whole subtree into existence at once, so
+				// walk it and add sub-watches for everything underneath.
+				n.addRecursive(normalizedName)
 			}
 
-			if !ok {
+			if event.Op&fsnotify.Chmod != 0 {
+				// A permission change isn't a content change, so it
+				// shouldn't trigger a command, but some filesystems bump
+				// mtime on chmod; refresh rescanTimes so the next periodic
+				// rescan doesn't mistake that bump for a missed write.
+				if n.RescanInterval > 0 {
+					n.mu.Lock()
+					n.rescanTimes[normalizedName] = time.GetFileModifiedTime(normalizedName)
+					n.mu.Unlock()
+				}
 				continue
 			}
+
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 && n.isWatchedDir(normalizedName) {
+				n.removeWatchedDir(normalizedName)
+				continue
+			}
+
+			if event.Op&fsnotify.Remove != 0 {
+				n.handleRemove(normalizedName)
+				continue
+			}
+
 			if !n.shouldExecute(normalizedName, event.Op) {
 				continue
 			}
 			logger.Debug("notified: %s: %s", normalizedName, event.Op)
 			now := time.Now()
+			n.mu.Lock()
 			n.times[normalizedName] = now
+			n.mu.Unlock()
 			e := Event{
 				Name: normalizedName,
+				Op:   event.Op,
 				Time: now,
 			}
 			n.Events <- e
@@ -177,6 +331,102 @@ func (n *Notify) wait() {
 	}
 }
 
+// addRecursive adds a watch on dir and every directory nested under it, so a
+// subtree that appears all at once (e.g. `mv src new`) is fully watched
+// immediately instead of only at its top level.
+func (n *Notify) addRecursive(dir string) {
+	if err := n.watcher.Add(dir); err != nil {
+		logger.Error("%s: %v", dir, err)
+		return
+	}
+	logger.Info("gazing at: %s", dir)
+	n.setWatchedDir(dir)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			n.addRecursive(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
+
+// removeWatchedDir drops the fsnotify watch on a directory that was renamed
+// or removed and prunes every cached modtime under it, so the watcher and
+// its internal state don't silently stop delivering events or leak entries
+// for files that no longer exist.
+func (n *Notify) removeWatchedDir(dir string) {
+	n.watcher.Remove(dir)
+
+	n.mu.Lock()
+	delete(n.watchedDirs, dir)
+	pruneUnder(n.times, dir)
+	pruneUnder(n.rescanTimes, dir)
+	n.mu.Unlock()
+
+	logger.Info("stopped gazing at: %s", dir)
+}
+
+// handleRemove prunes cached state for a removed file and, if DetectRemove
+// is enabled, surfaces a removal Event so users can bind commands to file
+// deletion.
+func (n *Notify) handleRemove(filePath string) {
+	n.mu.Lock()
+	delete(n.times, filePath)
+	delete(n.rescanTimes, filePath)
+	n.mu.Unlock()
+
+	if !n.DetectRemove || !patternsMatch(n.patterns, filePath) {
+		return
+	}
+	logger.Debug("notified: %s: %s", filePath, fsnotify.Remove)
+	n.Events <- Event{Name: filePath, Op: fsnotify.Remove, Time: time.Now()}
+}
+
+// isWatchedDir reports whether dir currently has an fsnotify watch.
+func (n *Notify) isWatchedDir(dir string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.watchedDirs[dir]
+}
+
+// setWatchedDir records dir as watched.
+func (n *Notify) setWatchedDir(dir string) {
+	n.mu.Lock()
+	n.watchedDirs[dir] = true
+	n.mu.Unlock()
+}
+
+// hasTime reports whether path has a cached last-execution time.
+func (n *Notify) hasTime(path string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, ok := n.times[path]
+	return ok
+}
+
+// pruneUnder deletes every entry in m whose key is dir or nested under it.
+// Callers must hold n.mu.
+func pruneUnder(m map[string]int64, dir string) {
+	prefix := dir + string(filepath.Separator)
+	for k := range m {
+		if k == dir || strings.HasPrefix(k, prefix) {
+			delete(m, k)
+		}
+	}
+}
+
+func patternsMatch(patterns []string, path string) bool {
+	for _, p := range patterns {
+		if MatchPattern(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
 func (n *Notify) shouldExecute(filePath string, op Op) bool {
 	const W = fsnotify.Write
 	const R = fsnotify.Rename
@@ -187,7 +437,9 @@ func (n *Notify) shouldExecute(filePath string, op Op) bool {
 		return false
 	}
 
+	n.mu.Lock()
 	lastExecutionTime := n.times[filePath]
+	n.mu.Unlock()
 
 	if !fs.IsFile(filePath) {
 		logger.Debug("skipped: %s: %s (not a file)", filePath, op)
@@ -216,6 +468,76 @@ func (n *Notify) shouldExecute(filePath string, op Op) bool {
 	return true
 }
 
+func (n *Notify) rescanLoop() {
+	ticker := stdtime.NewTicker(n.RescanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n.rescan()
+		case <-n.done:
+			return
+		}
+	}
+}
+
+// rescan walks every pattern-matched file, comparing its modtime against
+// rescanTimes, and synthesizes an Event for anything fsnotify missed. It
+// shares shouldExecute's debounce logic with the fsnotify path, so a real
+// inotify event immediately followed by a scan tick doesn't fire twice.
+func (n *Notify) rescan() {
+	seen := make(map[string]bool)
+
+	for _, pattern := range n.patterns {
+		files, _ := fs.Find(pattern)
+		for _, f := range files {
+			normalizedName := filepath.Clean(f)
+			seen[normalizedName] = true
+
+			modifiedTime := time.GetFileModifiedTime(normalizedName)
+			n.mu.Lock()
+			lastScanTime, known := n.rescanTimes[normalizedName]
+			n.rescanTimes[normalizedName] = modifiedTime
+			n.mu.Unlock()
+
+			if known && modifiedTime <= lastScanTime {
+				continue
+			}
+			if !n.shouldExecute(normalizedName, fsnotify.Write) {
+				continue
+			}
+			logger.Debug("rescanned: %s", normalizedName)
+			now := time.Now()
+			n.mu.Lock()
+			n.times[normalizedName] = now
+			n.mu.Unlock()
+			n.Events <- Event{Name: normalizedName, Op: fsnotify.Write, Time: now}
+		}
+	}
+
+	n.mu.Lock()
+	disappeared := make([]string, 0)
+	for path := range n.rescanTimes {
+		if seen[path] {
+			continue
+		}
+		disappeared = append(disappeared, path)
+	}
+	for _, path := range disappeared {
+		delete(n.rescanTimes, path)
+		delete(n.times, path)
+	}
+	n.mu.Unlock()
+
+	for _, path := range disappeared {
+		logger.Debug("rescan: disappeared: %s", path)
+		if n.DetectRemove {
+			n.Events <- Event{Name: path, Op: fsnotify.Remove, Time: time.Now()}
+		}
+	}
+}
+
 // PendingPeriod sets new pendingPeriod(ms).
 func (n *Notify) PendingPeriod(p int64) {
 	n.pendingPeriod = p
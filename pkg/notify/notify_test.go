@@ -0,0 +1,123 @@
+/**
+ * Gaze (https://github.com/wtetsu/gaze/)
+ * Copyright 2020-present wtetsu
+ * Licensed under MIT
+ */
+
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	stdtime "time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func waitForEvent(t *testing.T, n *Notify, timeout stdtime.Duration) (Event, bool) {
+	t.Helper()
+	select {
+	case e := <-n.Events:
+		return e, true
+	case <-stdtime.After(timeout):
+		return Event{}, false
+	}
+}
+
+func waitUntil(timeout stdtime.Duration, cond func() bool) bool {
+	deadline := stdtime.Now().Add(timeout)
+	for stdtime.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		stdtime.Sleep(10 * stdtime.Millisecond)
+	}
+	return cond()
+}
+
+// TestDirectoryRenameAndRemoveCleanUpState covers renaming and removing a
+// nested, watched directory: the stale fsnotify watch and every cached
+// modtime under it must be cleaned up, and a directory recreated under the
+// new name must be rewatched automatically.
+func TestDirectoryRenameAndRemoveCleanUpState(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	nestedFile := filepath.Join(sub, "a.txt")
+	if err := os.WriteFile(nestedFile, []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	n, err := New([]string{filepath.Join(root, "**", "*.txt")}, 100, 0, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer n.Close()
+
+	if !n.isWatchedDir(sub) {
+		t.Fatalf("expected %s to already be watched", sub)
+	}
+
+	renamed := filepath.Join(root, "renamed")
+	if err := os.Rename(sub, renamed); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if !waitUntil(2*stdtime.Second, func() bool { return !n.isWatchedDir(sub) }) {
+		t.Fatalf("expected watch on %s to be removed after rename", sub)
+	}
+	if n.hasTime(nestedFile) {
+		t.Fatalf("expected cached modtime for %s to be pruned after rename", nestedFile)
+	}
+
+	if err := os.MkdirAll(filepath.Join(renamed, "child"), 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if !waitUntil(2*stdtime.Second, func() bool { return n.isWatchedDir(renamed) }) {
+		t.Fatalf("expected %s to be rewatched after it reappeared", renamed)
+	}
+
+	if err := os.RemoveAll(renamed); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	if !waitUntil(2*stdtime.Second, func() bool { return !n.isWatchedDir(renamed) }) {
+		t.Fatalf("expected watch on %s to be removed after RemoveAll", renamed)
+	}
+}
+
+// TestDetectRemoveSurfacesEvent checks that, with DetectRemove enabled, a
+// removed file is surfaced as a Remove Event and its cached modtime is pruned.
+func TestDetectRemoveSurfacesEvent(t *testing.T) {
+	root := t.TempDir()
+	filePath := filepath.Join(root, "a.txt")
+	if err := os.WriteFile(filePath, []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	n, err := New([]string{filepath.Join(root, "*.txt")}, 100, 0, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer n.Close()
+	n.DetectRemove = true
+
+	if err := os.Remove(filePath); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	event, ok := waitForEvent(t, n, 2*stdtime.Second)
+	if !ok {
+		t.Fatalf("expected a removal event for %s", filePath)
+	}
+	if event.Name != filepath.Clean(filePath) {
+		t.Fatalf("got event for %q, want %q", event.Name, filePath)
+	}
+	if event.Op != fsnotify.Remove {
+		t.Fatalf("got Op %v, want %v", event.Op, fsnotify.Remove)
+	}
+	if n.hasTime(event.Name) {
+		t.Fatalf("expected cached modtime for %s to be pruned", event.Name)
+	}
+}
@@ -0,0 +1,289 @@
+/**
+ * Gaze (https://github.com/wtetsu/gaze/)
+ * Copyright 2020-present wtetsu
+ * Licensed under MIT
+ */
+
+// Package scheduler decides how a stream of per-file command triggers turns
+// into actual command executions: run them one at a time, run several in
+// parallel, coalesce bursts into a single run, or kill-and-restart. Gazer
+// supplies the RunFunc; the strategy only owns the queueing policy.
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RunFunc executes cmd for the given files. Implementations should respect
+// ctx's cancellation, which the Restart strategy uses to kill an in-flight
+// run superseded by a newer one.
+type RunFunc func(ctx context.Context, cmd string, files []string)
+
+// Strategy queues per-file command triggers and decides when and how to
+// turn them into executions via the RunFunc it was built with.
+type Strategy interface {
+	// Submit schedules cmd to run for file, subject to the strategy's
+	// policy (it may run immediately, queue, coalesce, or supersede an
+	// in-flight run).
+	Submit(cmd string, file string)
+	// Close stops accepting new work and releases any background
+	// goroutines. Parallel's Close waits for its in-flight runs to finish;
+	// the other strategies' Close returns without waiting for them.
+	Close()
+}
+
+// Serial queues every submission and runs them one at a time, in order. A
+// file that's already queued isn't queued again, so a burst of writes to
+// the same file collapses into a single pending run.
+func Serial(run RunFunc) Strategy {
+	s := &serial{
+		run:    run,
+		events: make(chan event, 256),
+		queued: make(map[string]bool),
+		done:   make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+type event struct {
+	cmd  string
+	file string
+}
+
+type serial struct {
+	run    RunFunc
+	events chan event
+	mu     sync.Mutex
+	queued map[string]bool
+	done   chan struct{}
+}
+
+func (s *serial) Submit(cmd string, file string) {
+	s.mu.Lock()
+	if s.queued[file] {
+		s.mu.Unlock()
+		return
+	}
+	s.queued[file] = true
+	s.mu.Unlock()
+
+	select {
+	case s.events <- event{cmd: cmd, file: file}:
+	case <-s.done:
+	}
+}
+
+func (s *serial) loop() {
+	for {
+		select {
+		case e := <-s.events:
+			s.mu.Lock()
+			delete(s.queued, e.file)
+			s.mu.Unlock()
+			s.run(context.Background(), e.cmd, []string{e.file})
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *serial) Close() {
+	close(s.done)
+}
+
+// Parallel runs up to n commands concurrently. A command string already
+// running is not started again until its current run finishes; instead, the
+// most recent submission for it while busy is queued and runs immediately
+// after, so a burst of triggers for the same command coalesces into one
+// rerun rather than being dropped.
+func Parallel(n int, run RunFunc) Strategy {
+	return &parallel{
+		run:     run,
+		sem:     make(chan struct{}, n),
+		running: make(map[string]bool),
+		pending: make(map[string]string),
+		done:    make(chan struct{}),
+	}
+}
+
+type parallel struct {
+	run     RunFunc
+	sem     chan struct{}
+	mu      sync.Mutex
+	running map[string]bool
+	pending map[string]string // cmd -> latest file queued while cmd is running
+	wg      sync.WaitGroup
+	done    chan struct{}
+}
+
+func (p *parallel) Submit(cmd string, file string) {
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	p.mu.Lock()
+	if p.running[cmd] {
+		p.pending[cmd] = file
+		p.mu.Unlock()
+		return
+	}
+	p.running[cmd] = true
+	p.mu.Unlock()
+
+	p.start(cmd, file)
+}
+
+func (p *parallel) start(cmd string, file string) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		select {
+		case p.sem <- struct{}{}:
+		case <-p.done:
+			p.mu.Lock()
+			delete(p.running, cmd)
+			p.mu.Unlock()
+			return
+		}
+
+		p.run(context.Background(), cmd, []string{file})
+		<-p.sem
+
+		p.mu.Lock()
+		nextFile, hasNext := p.pending[cmd]
+		delete(p.pending, cmd)
+		if !hasNext {
+			delete(p.running, cmd)
+		}
+		p.mu.Unlock()
+
+		if hasNext {
+			p.start(cmd, nextFile)
+		}
+	}()
+}
+
+func (p *parallel) Close() {
+	close(p.done)
+	p.wg.Wait()
+}
+
+// Debounce coalesces every submission for a given cmd that arrives within
+// window of the last one for that cmd into a single run, fired once the
+// burst goes quiet, with the union of that cmd's touched files passed to
+// RunFunc. Distinct commands are debounced independently, so one command's
+// burst never steals another's files or runs in its place.
+func Debounce(window time.Duration, run RunFunc) Strategy {
+	return &debounce{
+		run:     run,
+		window:  window,
+		timers:  make(map[string]*time.Timer),
+		pending: make(map[string]map[string]bool),
+	}
+}
+
+type debounce struct {
+	run     RunFunc
+	window  time.Duration
+	mu      sync.Mutex
+	timers  map[string]*time.Timer     // cmd -> timer for that cmd's window
+	pending map[string]map[string]bool // cmd -> set of files
+	closed  bool
+}
+
+func (d *debounce) Submit(cmd string, file string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return
+	}
+	if d.pending[cmd] == nil {
+		d.pending[cmd] = make(map[string]bool)
+	}
+	d.pending[cmd][file] = true
+	if t := d.timers[cmd]; t != nil {
+		t.Stop()
+	}
+	d.timers[cmd] = time.AfterFunc(d.window, func() { d.fire(cmd) })
+}
+
+// fire runs cmd's accumulated pending files. It only touches cmd's own
+// entries, so a burst of traffic on another command never postpones or
+// steals this fire.
+func (d *debounce) fire(cmd string) {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	fileSet, ok := d.pending[cmd]
+	delete(d.pending, cmd)
+	delete(d.timers, cmd)
+	d.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	files := make([]string, 0, len(fileSet))
+	for f := range fileSet {
+		files = append(files, f)
+	}
+	d.run(context.Background(), cmd, files)
+}
+
+func (d *debounce) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	for _, t := range d.timers {
+		t.Stop()
+	}
+}
+
+// Restart runs the newest submission immediately, cancelling the context
+// passed to any still-running previous one so the caller can kill it. This
+// is Gazer's original kill-and-restart behavior, exposed as a Strategy.
+func Restart(run RunFunc) Strategy {
+	baseCtx, baseCancel := context.WithCancel(context.Background())
+	return &restart{run: run, baseCtx: baseCtx, baseCancel: baseCancel}
+}
+
+type restart struct {
+	run        RunFunc
+	mu         sync.Mutex
+	cancel     context.CancelFunc
+	baseCtx    context.Context
+	baseCancel context.CancelFunc
+}
+
+func (r *restart) Submit(cmd string, file string) {
+	r.mu.Lock()
+	if r.baseCtx.Err() != nil {
+		r.mu.Unlock()
+		return
+	}
+	if r.cancel != nil {
+		r.cancel()
+	}
+	ctx, cancel := context.WithCancel(r.baseCtx)
+	r.cancel = cancel
+	r.mu.Unlock()
+
+	go r.run(ctx, cmd, []string{file})
+}
+
+func (r *restart) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.baseCancel()
+}
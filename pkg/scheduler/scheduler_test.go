@@ -0,0 +1,235 @@
+/**
+ * Gaze (https://github.com/wtetsu/gaze/)
+ * Copyright 2020-present wtetsu
+ * Licensed under MIT
+ */
+
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !cond() {
+		t.Fatalf("condition not met within %s", timeout)
+	}
+}
+
+func TestSerialRunsEverySubmittedFile(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string]int)
+
+	s := Serial(func(ctx context.Context, cmd string, files []string) {
+		mu.Lock()
+		seen[files[0]]++
+		mu.Unlock()
+	})
+	defer s.Close()
+
+	for _, f := range []string{"a.go", "b.go", "c.go"} {
+		s.Submit("echo", f)
+	}
+
+	waitFor(t, time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(seen) == 3
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, f := range []string{"a.go", "b.go", "c.go"} {
+		if seen[f] != 1 {
+			t.Errorf("seen[%q] = %d, want 1", f, seen[f])
+		}
+	}
+}
+
+func TestParallelLimitsConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	var current, maxConcurrent int
+	release := make(chan struct{})
+
+	p := Parallel(2, func(ctx context.Context, cmd string, files []string) {
+		mu.Lock()
+		current++
+		if current > maxConcurrent {
+			maxConcurrent = current
+		}
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		current--
+		mu.Unlock()
+	})
+	defer p.Close()
+
+	p.Submit("cmd-a", "a.go")
+	p.Submit("cmd-b", "b.go")
+	p.Submit("cmd-c", "c.go")
+
+	waitFor(t, time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return current == 2
+	})
+
+	close(release)
+
+	mu.Lock()
+	got := maxConcurrent
+	mu.Unlock()
+	if got > 2 {
+		t.Fatalf("maxConcurrent = %d, want at most 2", got)
+	}
+}
+
+// TestParallelQueuesRerunForBusyCommand is a regression test: Submit used to
+// silently drop a submission for a command that was already running instead
+// of queueing a rerun.
+func TestParallelQueuesRerunForBusyCommand(t *testing.T) {
+	var mu sync.Mutex
+	runs := 0
+	hold := make(chan struct{})
+
+	p := Parallel(2, func(ctx context.Context, cmd string, files []string) {
+		mu.Lock()
+		runs++
+		first := runs == 1
+		mu.Unlock()
+		if first {
+			<-hold
+		}
+	})
+	defer p.Close()
+
+	p.Submit("build", "a.go")
+	waitFor(t, time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return runs == 1
+	})
+
+	p.Submit("build", "b.go")
+	close(hold)
+
+	waitFor(t, time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return runs == 2
+	})
+}
+
+// TestDebounceKeysPendingStateByCommand is a regression test: a shared
+// cmd/files pair used to let one command's burst steal another's files and
+// run in its place instead of each command firing independently.
+func TestDebounceKeysPendingStateByCommand(t *testing.T) {
+	var mu sync.Mutex
+	runsByCmd := make(map[string][]string)
+
+	d := Debounce(30*time.Millisecond, func(ctx context.Context, cmd string, files []string) {
+		mu.Lock()
+		runsByCmd[cmd] = append([]string{}, files...)
+		mu.Unlock()
+	})
+	defer d.Close()
+
+	d.Submit("cmd-a", "a.go")
+	d.Submit("cmd-b", "b.go")
+
+	waitFor(t, time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(runsByCmd) == 2
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := runsByCmd["cmd-a"]; len(got) != 1 || got[0] != "a.go" {
+		t.Errorf("runsByCmd[cmd-a] = %v, want [a.go]", got)
+	}
+	if got := runsByCmd["cmd-b"]; len(got) != 1 || got[0] != "b.go" {
+		t.Errorf("runsByCmd[cmd-b] = %v, want [b.go]", got)
+	}
+}
+
+// TestDebounceTimerIsPerCommand is a regression test: a single shared timer
+// used to let ongoing traffic on one command reset the window for every
+// command, indefinitely postponing another command's already-due fire.
+func TestDebounceTimerIsPerCommand(t *testing.T) {
+	var mu sync.Mutex
+	runsByCmd := make(map[string][]string)
+
+	d := Debounce(50*time.Millisecond, func(ctx context.Context, cmd string, files []string) {
+		mu.Lock()
+		runsByCmd[cmd] = append([]string{}, files...)
+		mu.Unlock()
+	})
+	defer d.Close()
+
+	d.Submit("cmd-a", "a.go")
+
+	stop := time.After(200 * time.Millisecond)
+loop:
+	for {
+		select {
+		case <-stop:
+			break loop
+		default:
+			d.Submit("cmd-b", "b.go")
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	waitFor(t, time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		_, ok := runsByCmd["cmd-a"]
+		return ok
+	})
+
+	mu.Lock()
+	got := runsByCmd["cmd-a"]
+	mu.Unlock()
+	if len(got) != 1 || got[0] != "a.go" {
+		t.Errorf("runsByCmd[cmd-a] = %v, want [a.go]", got)
+	}
+}
+
+func TestRestartCancelsPreviousRun(t *testing.T) {
+	firstCtx := make(chan context.Context, 1)
+
+	r := Restart(func(ctx context.Context, cmd string, files []string) {
+		select {
+		case firstCtx <- ctx:
+		default:
+		}
+		<-ctx.Done()
+	})
+	defer r.Close()
+
+	r.Submit("serve", "a.go")
+	ctx := <-firstCtx
+
+	r.Submit("serve", "b.go")
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the first run's context to be canceled once superseded")
+	}
+}